@@ -0,0 +1,88 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file builds the router's BW enforcers from the on-disk
+// configuration in confDir.
+
+package main
+
+import (
+	log "github.com/inconshreveable/log15"
+
+	"github.com/netsec-ethz/scion/go/border/conf"
+	"github.com/netsec-ethz/scion/go/border/enforcement"
+	"github.com/netsec-ethz/scion/go/border/sched"
+)
+
+// setupBwEnforcement loads the BW policy from confDir and builds
+// r.ingressBWE/r.egresseBWE from it via the enforcement package's
+// control-plane API, so the same code path that an operator's live
+// ctrlapi update goes through is exercised on startup too. It also
+// applies the scheduler's per-class DRR weights and per-AS traffic
+// classes from the same config, so those reload alongside BW limits
+// instead of needing a separate mechanism (see reloadConfig).
+func (r *Router) setupBwEnforcement(confDir string) {
+	config, err := conf.LoadBWConfig(confDir)
+	if err != nil {
+		log.Warn("Unable to load BW enforcement config; enforcement disabled", err.Ctx...)
+		r.fBwEnf = false
+		r.ingressBWE = enforcement.NewBWEnforcer(false)
+		r.egresseBWE = enforcement.NewBWEnforcer(false)
+		return
+	}
+	r.fBwEnf = config.Enabled
+	r.ingressBWE = enforcement.NewBWEnforcer(config.Enabled)
+	r.egresseBWE = enforcement.NewBWEnforcer(config.Enabled)
+	buildEnforcer(r.ingressBWE, r.classifier, config.Ingress)
+	buildEnforcer(r.egresseBWE, r.classifier, config.Egress)
+	r.setSchedWeights(config.Weights)
+}
+
+// buildEnforcer installs every interface and AS reservation from ifConfs
+// into bwe, via the same setters the ctrlapi control plane uses. It also
+// applies each AS' configured traffic class, if any, to classifier, so
+// scheduling priority comes from the same per-AS config entry as its BW
+// reservation instead of a separate, easily-out-of-sync config.
+func buildEnforcer(bwe *enforcement.BWEnforcer, classifier *sched.DefaultClassifier,
+	ifConfs []conf.BWInterfaceConfig) {
+
+	for _, ifc := range ifConfs {
+		bwe.SetInterfaceBandwidth(ifc.IFID, ifc.MaxBw, ifc.Burst)
+		for _, as := range ifc.ASes {
+			if err := bwe.SetASBandwidth(ifc.IFID, as.IA, as.MaxBw, as.Burst, as.CeilFactor); err != nil {
+				log.Error("Unable to install AS BW reservation", err.Ctx...)
+			}
+			if as.Class == "" {
+				continue
+			}
+			class, ok := sched.ParseClass(as.Class)
+			if !ok {
+				log.Error("Unknown scheduling class in BW config", "isdAs", as.IA, "class", as.Class)
+				continue
+			}
+			classifier.SetASClass(as.IA, class)
+		}
+	}
+}
+
+// setSchedWeights applies weights as the scheduler's per-class DRR
+// quanta. A zero value means "not configured" (same convention as
+// SetASBandwidth's ceilFactor), so the previous weights are kept instead
+// of collapsing every class' quantum to zero.
+func (r *Router) setSchedWeights(weights [4]int64) {
+	if weights == ([4]int64{}) {
+		return
+	}
+	r.schedWeights.Store(weights)
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file sends SCMP_T_POLICY_BW_EXCEEDED notifications for packets
+// that the BW enforcer drops, so a well-behaved sender can back off
+// instead of treating the drop as ordinary loss and retransmitting into
+// an overload. The notifications are themselves rate-limited per source
+// AS, so this path can't be abused to amplify traffic back at a victim.
+package main
+
+import (
+	"sync"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/netsec-ethz/scion/go/border/enforcement"
+	"github.com/netsec-ethz/scion/go/border/metrics"
+	"github.com/netsec-ethz/scion/go/border/rpkt"
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/scmp"
+)
+
+const (
+	// scmpBwRate/scmpBwBurst bound how many BW-exceeded notifications a
+	// single source AS can receive per second, independent of how many
+	// packets of theirs are actually being dropped.
+	scmpBwRate  = 5
+	scmpBwBurst = 5
+)
+
+// bwScmpLimiter rate-limits SCMP_T_POLICY_BW_EXCEEDED notifications on a
+// per-source-AS basis, creating a RateLimiter for an AS the first time
+// it's seen.
+type bwScmpLimiter struct {
+	mu   sync.Mutex
+	ases map[uint32]*enforcement.RateLimiter
+}
+
+func newBwScmpLimiter() *bwScmpLimiter {
+	return &bwScmpLimiter{ases: make(map[uint32]*enforcement.RateLimiter)}
+}
+
+func (l *bwScmpLimiter) allow(isdas addr.ISD_AS) bool {
+	key := isdas.Uint32()
+	l.mu.Lock()
+	rl, ok := l.ases[key]
+	if !ok {
+		rl = enforcement.NewRateLimiter(scmpBwRate, scmpBwBurst)
+		l.ases[key] = rl
+	}
+	l.mu.Unlock()
+	return rl.Allow(1)
+}
+
+// notifyBwExceeded builds and routes an SCMP_T_POLICY_BW_EXCEEDED packet
+// back towards rp's source, unless this source AS has already received
+// its quota of such notifications for this interval.
+func (r *Router) notifyBwExceeded(rp *rpkt.RtrPkt, v *enforcement.Violation) {
+	if !r.bwScmpLimiter.allow(v.IA) {
+		return
+	}
+	info := &scmp.BWExceededInfo{
+		IA:           v.IA,
+		IfID:         v.IfID,
+		ObservedRate: v.Observed,
+		Limit:        v.Limit,
+	}
+	reply, err := rp.CreateReplyScnPkt(scmp.SCMP_CLASS_POLICY, scmp.SCMP_T_POLICY_BW_EXCEEDED, info)
+	if err != nil {
+		log.Error("Unable to create SCMP_T_POLICY_BW_EXCEEDED reply", err.Ctx...)
+		return
+	}
+	if err := reply.Route(); err != nil {
+		log.Error("Unable to route SCMP_T_POLICY_BW_EXCEEDED reply", err.Ctx...)
+		return
+	}
+	metrics.PktsScmpBwExceeded.With(prometheus.Labels{
+		"isd_as": v.IA.String(),
+		"ifid":   v.IfID.String(),
+	}).Inc()
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/scion/go/lib/addr"
+)
+
+func TestBwScmpLimiterAllowsBurstThenBlocks(t *testing.T) {
+	l := newBwScmpLimiter()
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	for i := 0; i < scmpBwBurst; i++ {
+		if !l.allow(isdas) {
+			t.Fatalf("expected notification %d/%d within burst to be allowed", i+1, scmpBwBurst)
+		}
+	}
+	if l.allow(isdas) {
+		t.Fatal("expected a notification beyond the burst to be rate-limited")
+	}
+}
+
+func TestBwScmpLimiterPerAS(t *testing.T) {
+	l := newBwScmpLimiter()
+	a := addr.ISD_AS{I: 1, A: 10}
+	b := addr.ISD_AS{I: 1, A: 20}
+	for i := 0; i < scmpBwBurst; i++ {
+		l.allow(a)
+	}
+	if !l.allow(b) {
+		t.Fatal("a different source AS must have its own independent quota")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements the ctrlapi.CtrlAPI gRPC service (see ctrlapi.proto)
+// that lets an operator update a router's BW enforcement policy live,
+// instead of editing the config file on disk and sending SIGHUP. The
+// service is also exposed as JSON/HTTP via grpc-gateway, generated into
+// ctrlapipb alongside the gRPC stubs.
+package ctrlapi
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	log "github.com/inconshreveable/log15"
+
+	"github.com/netsec-ethz/scion/go/border/ctrlapi/ctrlapipb"
+	"github.com/netsec-ethz/scion/go/border/enforcement"
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+const (
+	// DefaultGRPCAddr is the default bind address for the gRPC listener.
+	DefaultGRPCAddr = "127.0.0.1:30252"
+	// DefaultHTTPAddr is the default bind address for the grpc-gateway
+	// JSON/HTTP listener.
+	DefaultHTTPAddr = "127.0.0.1:30253"
+)
+
+// Enforcers bundles the two BWEnforcers a router runs, one per direction,
+// so the control API can address either of them.
+type Enforcers struct {
+	Ingress *enforcement.BWEnforcer
+	Egress  *enforcement.BWEnforcer
+}
+
+func (e Enforcers) pick(dir ctrlapipb.Direction) *enforcement.BWEnforcer {
+	if dir == ctrlapipb.Direction_EGRESS {
+		return e.Egress
+	}
+	return e.Ingress
+}
+
+// Server implements ctrlapipb.CtrlAPIServer, and serves it over both gRPC
+// and (via grpc-gateway) JSON/HTTP.
+type Server struct {
+	GRPCAddr string
+	HTTPAddr string
+	// AuthToken, if non-empty, must be presented as the "authorization"
+	// gRPC metadata entry by every caller.
+	AuthToken string
+	// TLS, if set, is used for the gRPC listener; nil means plaintext,
+	// which is only appropriate when GRPCAddr is loopback-only.
+	TLS credentials.TransportCredentials
+
+	enforcers Enforcers
+	reload    func() *common.Error
+}
+
+// NewServer creates a Server bound to the default addresses; callers
+// adjust GRPCAddr/HTTPAddr/AuthToken/TLS before calling ListenAndServe.
+func NewServer(enforcers Enforcers, reload func() *common.Error) *Server {
+	return &Server{
+		GRPCAddr:  DefaultGRPCAddr,
+		HTTPAddr:  DefaultHTTPAddr,
+		enforcers: enforcers,
+		reload:    reload,
+	}
+}
+
+// ListenAndServe starts the gRPC listener and its grpc-gateway JSON/HTTP
+// mapping. It blocks, and only returns once one of the two listeners
+// fails.
+func (s *Server) ListenAndServe() *common.Error {
+	lis, e := net.Listen("tcp", s.GRPCAddr)
+	if e != nil {
+		return common.NewError("Unable to listen for ctrlapi gRPC", "addr", s.GRPCAddr, "err", e)
+	}
+	var opts []grpc.ServerOption
+	if s.TLS != nil {
+		opts = append(opts, grpc.Creds(s.TLS))
+	}
+	opts = append(opts, grpc.UnaryInterceptor(s.authUnary), grpc.StreamInterceptor(s.authStream))
+	gs := grpc.NewServer(opts...)
+	ctrlapipb.RegisterCtrlAPIServer(gs, s)
+
+	errc := make(chan error, 2)
+	go func() { errc <- gs.Serve(lis) }()
+	go func() {
+		mux := runtime.NewServeMux()
+		dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+		if e := ctrlapipb.RegisterCtrlAPIHandlerFromEndpoint(context.Background(), mux, s.GRPCAddr,
+			dialOpts); e != nil {
+			errc <- e
+			return
+		}
+		errc <- http.ListenAndServe(s.HTTPAddr, mux)
+	}()
+	return common.NewError("ctrlapi server stopped", "err", <-errc)
+}
+
+// authUnary rejects unary calls that don't carry the configured
+// AuthToken. It's a no-op when AuthToken is empty, e.g. in tests or
+// behind a trusted loopback-only deployment.
+func (s *Server) authUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if e := s.checkAuth(ctx); e != nil {
+		return nil, e
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if e := s.checkAuth(ss.Context()); e != nil {
+		return e
+	}
+	return handler(srv, ss)
+}
+
+func (s *Server) checkAuth(ctx context.Context) error {
+	if s.AuthToken == "" {
+		return nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["authorization"]) != 1 || md["authorization"][0] != s.AuthToken {
+		return grpc.Errorf(codes.Unauthenticated, "missing or invalid authorization token")
+	}
+	return nil
+}
+
+func (s *Server) SetASBandwidth(ctx context.Context, req *ctrlapipb.SetASBandwidthRequest) (
+	*ctrlapipb.Ack, error) {
+	isdas, e := addr.IAFromString(req.IsdAs)
+	if e != nil {
+		return nil, e
+	}
+	bwe := s.enforcers.pick(req.Direction)
+	if err := bwe.SetASBandwidth(common.IFIDType(req.Ifid), *isdas, req.MaxBw, req.Burst,
+		req.CeilFactor); err != nil {
+		log.Error("ctrlapi: SetASBandwidth failed", err.Ctx...)
+		return nil, err
+	}
+	return &ctrlapipb.Ack{}, nil
+}
+
+func (s *Server) SetInterfaceBandwidth(ctx context.Context, req *ctrlapipb.SetInterfaceBandwidthRequest) (
+	*ctrlapipb.Ack, error) {
+	bwe := s.enforcers.pick(req.Direction)
+	bwe.SetInterfaceBandwidth(common.IFIDType(req.Ifid), req.MaxBw, req.Burst)
+	return &ctrlapipb.Ack{}, nil
+}
+
+func (s *Server) GetEnforcementStats(ctx context.Context, req *ctrlapipb.GetEnforcementStatsRequest) (
+	*ctrlapipb.GetEnforcementStatsResponse, error) {
+	bwe := s.enforcers.pick(req.Direction)
+	stats, err := bwe.GetEnforcementStats(common.IFIDType(req.Ifid))
+	if err != nil {
+		return nil, err
+	}
+	resp := &ctrlapipb.GetEnforcementStatsResponse{Ifid: req.Ifid, IfBucketLevel: stats.IfBucketLevel}
+	for _, as := range stats.ASes {
+		resp.AsStats = append(resp.AsStats, &ctrlapipb.ASStats{
+			IsdAs:           as.IA.String(),
+			MaxBw:           as.MaxBw,
+			BucketLevel:     as.BucketLevel,
+			CeilBucketLevel: as.CeilBucketLevel,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) ListASLimits(ctx context.Context, req *ctrlapipb.ListASLimitsRequest) (
+	*ctrlapipb.ListASLimitsResponse, error) {
+	bwe := s.enforcers.pick(req.Direction)
+	limits, err := bwe.ListASLimits(common.IFIDType(req.Ifid))
+	if err != nil {
+		return nil, err
+	}
+	resp := &ctrlapipb.ListASLimitsResponse{}
+	for _, l := range limits {
+		resp.Limits = append(resp.Limits, &ctrlapipb.ASLimit{
+			IsdAs: l.IA.String(), MaxBw: l.MaxBw, Burst: l.Burst,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) ReloadConfig(ctx context.Context, req *ctrlapipb.ReloadConfigRequest) (
+	*ctrlapipb.Ack, error) {
+	if err := s.reload(); err != nil {
+		log.Error("ctrlapi: ReloadConfig failed", err.Ctx...)
+		return nil, err
+	}
+	return &ctrlapipb.Ack{}, nil
+}
+
+// WatchDrops streams drop events from the selected direction's enforcer,
+// only those of req.IsdAs if it is set, until the client disconnects.
+// Events that arrive while the subscription's buffer is full are skipped
+// (see enforcement.BWEnforcer.Subscribe).
+func (s *Server) WatchDrops(req *ctrlapipb.WatchDropsRequest, stream ctrlapipb.CtrlAPI_WatchDropsServer) error {
+	bwe := s.enforcers.pick(req.Direction)
+	ch, unsub := bwe.Subscribe()
+	defer unsub()
+	for {
+		select {
+		case ev := <-ch:
+			if req.IsdAs != "" && ev.IA.String() != req.IsdAs {
+				continue
+			}
+			if e := stream.Send(&ctrlapipb.DropEvent{
+				Direction:  req.Direction,
+				Ifid:       uint64(ev.IfID),
+				IsdAs:      ev.IA.String(),
+				Length:     int64(ev.Length),
+				TimeUnixNs: ev.Time.UnixNano(),
+			}); e != nil {
+				return e
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
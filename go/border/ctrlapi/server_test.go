@@ -0,0 +1,95 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctrlapi
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/netsec-ethz/scion/go/border/ctrlapi/ctrlapipb"
+	"github.com/netsec-ethz/scion/go/border/enforcement"
+)
+
+func TestCheckAuthNoToken(t *testing.T) {
+	s := &Server{}
+	if err := s.checkAuth(context.Background()); err != nil {
+		t.Fatalf("expected no error when AuthToken is unset, got %v", err)
+	}
+}
+
+func TestCheckAuthRejectsMissingMetadata(t *testing.T) {
+	s := &Server{AuthToken: "secret"}
+	if err := s.checkAuth(context.Background()); err == nil {
+		t.Fatal("expected an error when no metadata is present and AuthToken is set")
+	}
+}
+
+func TestCheckAuthAcceptsMatchingToken(t *testing.T) {
+	s := &Server{AuthToken: "secret"}
+	md := metadata.Pairs("authorization", "secret")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	if err := s.checkAuth(ctx); err != nil {
+		t.Fatalf("expected a matching token to be accepted, got %v", err)
+	}
+}
+
+func TestCheckAuthRejectsWrongToken(t *testing.T) {
+	s := &Server{AuthToken: "secret"}
+	md := metadata.Pairs("authorization", "wrong")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	if err := s.checkAuth(ctx); err == nil {
+		t.Fatal("expected a mismatched token to be rejected")
+	}
+}
+
+func TestSetInterfaceAndASBandwidth(t *testing.T) {
+	s := NewServer(Enforcers{
+		Ingress: enforcement.NewBWEnforcer(true),
+		Egress:  enforcement.NewBWEnforcer(true),
+	}, nil)
+	ctx := context.Background()
+
+	if _, err := s.SetInterfaceBandwidth(ctx, &ctrlapipb.SetInterfaceBandwidthRequest{
+		Direction: ctrlapipb.Direction_INGRESS,
+		Ifid:      1,
+		MaxBw:     1000,
+		Burst:     1000,
+	}); err != nil {
+		t.Fatalf("SetInterfaceBandwidth failed: %v", err)
+	}
+
+	if _, err := s.SetASBandwidth(ctx, &ctrlapipb.SetASBandwidthRequest{
+		Direction: ctrlapipb.Direction_INGRESS,
+		Ifid:      1,
+		IsdAs:     "1-10",
+		MaxBw:     100,
+		Burst:     100,
+	}); err != nil {
+		t.Fatalf("SetASBandwidth failed: %v", err)
+	}
+
+	resp, err := s.ListASLimits(ctx, &ctrlapipb.ListASLimitsRequest{
+		Direction: ctrlapipb.Direction_INGRESS,
+		Ifid:      1,
+	})
+	if err != nil {
+		t.Fatalf("ListASLimits failed: %v", err)
+	}
+	if len(resp.Limits) != 1 || resp.Limits[0].MaxBw != 100 {
+		t.Fatalf("ListASLimits = %+v, want a single 100-byte/s limit", resp.Limits)
+	}
+}
@@ -14,176 +14,344 @@
 
 // This file contains all logic to do the bandwidth enforcement within
 // the router.
-
+//
+// Enforcement is implemented as a hierarchical token bucket (HTB-style):
+// each IFEContainer is a parent bucket sized to the interface's capacity,
+// and each ASEInformation is a child bucket sized to the AS' reservation.
+// An AS that has exhausted its own bucket may borrow spare capacity from
+// the interface bucket, up to a configurable ceiling, so that bursts are
+// absorbed fairly instead of being smoothed (and mis-classified as
+// violations) by a moving average.
 package enforcement
 
 import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gavv/monotime"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/netsec-ethz/scion/go/border/metrics"
 	"github.com/netsec-ethz/scion/go/border/rpkt"
 	"github.com/netsec-ethz/scion/go/lib/addr"
 	"github.com/netsec-ethz/scion/go/lib/common"
-	"time"
 )
 
+// asMapType and ifaceMapType are the concrete types stored in
+// IFEContainer.ases and BWEnforcer.ifaces respectively. Each is swapped wholesale via atomic.Value so the
+// control API (see control.go) can update limits without the hot path
+// ever taking a lock.
+type asMapType map[uint32]*ASEInformation
+type ifaceMapType map[common.IFIDType]*IFEContainer
+
+// defaultCeilFactor is the default ratio between an AS' ceiling rate (the
+// max rate it may reach by borrowing from its interface) and its
+// guaranteed maxBw. A factor of 1.0 means an AS may never exceed its own
+// reservation, no matter how idle the interface is.
+const defaultCeilFactor = 1.0
+
+// defaultUnknownShare is the guaranteed rate handed to the shared bucket
+// used by ASes that have no explicit reservation on an interface, until
+// an operator overrides it via SetUnknownShare.
+const defaultUnknownShare = 1 << 20 // 1 MB/s
+
 type BWEnforcer struct {
 	// DoEnforcement indicates whether to do enforcement or not.
 	DoEnforcement bool
-	// Interfaces contains all interfaces that have ASes with
-	// reserved bandwidth.
-	Interfaces map[common.IFIDType]IFEContainer
+	// ifaces holds the current ifaceMapType, swapped atomically so that
+	// Check() never blocks on a concurrent control-API update.
+	ifaces atomic.Value
+}
+
+// NewBWEnforcer creates an empty enforcer; interfaces are added via
+// SetInterfaceBandwidth (see control.go).
+func NewBWEnforcer(doEnforcement bool) *BWEnforcer {
+	bwe := &BWEnforcer{DoEnforcement: doEnforcement}
+	bwe.ifaces.Store(make(ifaceMapType))
+	return bwe
 }
 
-// IFEContainer contains all information that is necessary to do
-// bandwidth enforcement per interface.
+// Interfaces returns the current snapshot of per-interface enforcers.
+func (bwe *BWEnforcer) Interfaces() map[common.IFIDType]*IFEContainer {
+	return bwe.ifaces.Load().(ifaceMapType)
+}
+
+// IFEContainer is the parent token bucket for a single interface
+// (ingress or egress). It holds the per-AS child buckets, and the
+// interface-wide bucket that children borrow spare capacity from.
 type IFEContainer struct {
-	// avgs holds all averages associated to an AS.
-	avgs map[uint32]*ASEInformation
-	// maxIfBw indicates the maximum bandwidth for the interface
-	// either ingress or egress
-	maxIfBw int64
-	// usedIfBw holds the currently used BW by all reserved ASes.
-	usedIfBw int64
-	// tUsedIfBw is the time stamp at which the usedIfBw was last updated.
-	tUsedIfBw time.Time
-	//unknown holds the current average for unknown ASes.
-	unknown ASEInformation
-}
-
-// ASEInformation contains all information necessary to do bandwidth
-// enforcement for a certain AS.
+	// ases holds the current asMapType, swapped atomically on updates.
+	ases atomic.Value
+	// unknown is the shared child bucket for ASes without a reservation.
+	unknown *ASEInformation
+	// ifBucket is the parent bucket, sized to the interface's capacity.
+	ifBucket *tokenBucket
+	// ifLabels holds the prometheus labels for interface-level metrics,
+	// keyed by ifid only; it must not carry isd_as, or IfBucketLevel would
+	// grow one series per AS instead of one per interface.
+	ifLabels prometheus.Labels
+}
+
+// ASEInformation is the child token bucket for a single AS.
 type ASEInformation struct {
-	// maxBw indicates the max bandwidth that the AS is allowed to use.
+	// IA is the AS this bucket belongs to. It's the zero value for the
+	// shared bucket used by unknown ASes.
+	IA addr.ISD_AS
+	// maxBw is the guaranteed bandwidth (bytes/s) the AS may always use.
+	// It's accessed only via atomic, so a control-API update (see
+	// control.go) can change it in place without replacing the bucket.
 	maxBw int64
-	// alertBW indicates the bandwidth that is used for alerting. currently it is set to 95%.
-	alertBW int64
-	// curBw holds the current used BW of the AS.
-	curBw int64
-	// movAvg holds the current bandwidth average of the AS.
-	movAvg *MovingAverage
+	// bucket enforces maxBw; it refills at the guaranteed rate.
+	bucket *tokenBucket
+	// ceil enforces the AS' ceiling rate, i.e. the most it may reach by
+	// borrowing from the interface bucket. It prevents a single AS from
+	// starving its neighbours even when the interface is otherwise idle.
+	ceil *tokenBucket
 	// Labels holds the prometheus labels of the AS.
 	Labels prometheus.Labels
 }
 
-// Check() indicates whether a packet should be forwarded to the next stage
-// of the router or not.
-func (bwe *BWEnforcer) Check(rp *rpkt.RtrPkt) bool {
-	ifid, _ := rp.IFCurr()
-	if ifInfo, ex := bwe.Interfaces[*ifid]; ex {
-		srcIA, _ := rp.SrcIA()
-		length := len(rp.Raw)
-		return ifInfo.canForward(srcIA, length)
+// NewIFEContainer creates a parent bucket for an interface with the given
+// capacity and burst allowance (bytes/s, bytes).
+func NewIFEContainer(ifid common.IFIDType, maxIfBw, burst int64) *IFEContainer {
+	ifec := &IFEContainer{
+		unknown: NewASEInformation(addr.ISD_AS{}, defaultUnknownShare, burst, defaultCeilFactor,
+			asLabels(ifid, addr.ISD_AS{})),
+		ifBucket: newTokenBucket(maxIfBw, burst),
+		ifLabels: prometheus.Labels{"ifid": ifid.String()},
 	}
-	return true
+	ifec.ases.Store(make(asMapType))
+	return ifec
 }
 
-// canForward() indicates whether a packet is allowed to pass the router. It is not if
-// the AS exceeds its bandwidth limit.
-func (ifec *IFEContainer) canForward2(isdas *addr.ISD_AS, length int) bool {
-	info := ifec.getBWInfo(*isdas)
-	labels := info.Labels
-
-	//If there is unlimited BW for an AS just forward the packet.
-	if info.maxBw == -1 {
-		return true
+// AddAS installs a child bucket for isdas, replacing any existing one, via
+// copy-on-write so concurrent readers in canForward never see a partially
+// updated map. A nil labels is replaced by the AS' ifid/isd_as labels.
+func (ifec *IFEContainer) AddAS(isdas addr.ISD_AS, maxBw, burst int64, ceilFactor float64,
+	labels prometheus.Labels) {
+	if labels == nil {
+		labels = prometheus.Labels{"ifid": ifec.ifLabels["ifid"], "isd_as": isdas.String()}
 	}
+	old := ifec.asMap()
+	next := make(asMapType, len(old)+1)
+	for k, v := range old {
+		next[k] = v
+	}
+	next[isdas.Uint32()] = NewASEInformation(isdas, maxBw, burst, ceilFactor, labels)
+	ifec.ases.Store(next)
+}
 
-	//If there is no BW assigned to an AS just drop the packet.
-	if info.maxBw == 0 {
-		return false
+// asMap returns the current snapshot of per-AS child buckets.
+func (ifec *IFEContainer) asMap() asMapType {
+	return ifec.ases.Load().(asMapType)
+}
+
+// NewASEInformation creates a child bucket for an AS. ceilFactor is the
+// multiplier applied to maxBw to get the AS' borrowing ceiling; a
+// ceilFactor <= 1.0 disables borrowing for that AS entirely.
+func NewASEInformation(isdas addr.ISD_AS, maxBw, burst int64, ceilFactor float64,
+	labels prometheus.Labels) *ASEInformation {
+	return &ASEInformation{
+		IA:     isdas,
+		maxBw:  maxBw,
+		bucket: newTokenBucket(maxBw, burst),
+		ceil:   newTokenBucket(int64(float64(maxBw)*ceilFactor), burst),
+		Labels: labels,
 	}
+}
+
+// Violation carries enough context about a dropped packet for the caller
+// to build an SCMP_T_POLICY_BW_EXCEEDED notification (see go/lib/scmp and
+// Router.processPacket), without the enforcement package needing to know
+// anything about SCMP itself.
+type Violation struct {
+	IfID common.IFIDType
+	IA   addr.ISD_AS
+	// Limit is the AS' configured reservation, in bytes/s.
+	Limit int64
+	// Observed approximates the rate that tripped the limit: the ceiling
+	// the AS hit, or its guaranteed rate if the interface itself had no
+	// spare capacity to lend. The token bucket doesn't track an explicit
+	// moving rate, so this is the threshold crossed, not a measurement.
+	Observed int64
+}
 
-	avg := info.getAvg()
-	if avg < info.maxBw {
-		info.addPktToAvg2(length)
-		if avg > info.alertBW {
-			metrics.CurBwPerAs.With(labels).Set(float64(avg))
+// Check indicates whether a packet should be forwarded to the next stage
+// of the router or not. On a drop, it also returns the Violation that
+// caused it, for SCMP notification purposes.
+func (bwe *BWEnforcer) Check(rp *rpkt.RtrPkt) (bool, *Violation) {
+	ifid, _ := rp.IFCurr()
+	if ifec, ex := bwe.Interfaces()[*ifid]; ex {
+		srcIA, _ := rp.SrcIA()
+		ok, v := ifec.canForward(*ifid, *srcIA, len(rp.Raw))
+		if !ok {
+			bwe.reportDrop(*ifid, *srcIA, len(rp.Raw))
 		}
+		return ok, v
+	}
+	return true, nil
+}
 
-		return true
+// canForward indicates whether a packet of the given length is allowed to
+// pass the router. It is not if the AS exceeds its bandwidth reservation
+// and the interface has no spare capacity to lend it.
+func (ifec *IFEContainer) canForward(ifid common.IFIDType, isdas addr.ISD_AS, length int) (bool, *Violation) {
+	asInfo, known := ifec.asMap()[isdas.Uint32()]
+	if !known {
+		asInfo = ifec.unknown
 	}
+	maxBw := atomic.LoadInt64(&asInfo.maxBw)
 
-	metrics.CurBwPerAs.With(labels).Set(float64(avg))
-	metrics.PktsDropPerAs.With(labels).Inc()
-	return false
-}
-
-// canForward() indicates whether a packet is allowed to pass the router. It is not if
-// the AS exceeds its bandwidth limit.
-func (ifec *IFEContainer) canForward(isdas *addr.ISD_AS, length int) bool {
-	asInfo, exists := ifec.getBWInfo(*isdas)
-	if exists {
-		oldAsBw, curAsBw := asInfo.getAvgs(false)
-		if curAsBw < asInfo.maxBw {
-			asInfo.addPktToAvg(length, false)
-			ifec.usedIfBw -= oldAsBw
-			ifec.usedIfBw += curAsBw
-			return true
-		}
-	} else {
-		_, curAsBw := asInfo.getAvgs(true)
-		freeIfBw := ifec.maxIfBw - ifec.getUsedIfBw()
-		// 0.75 * maxIFBw && (curAsBw < maxAsBw || curAsBw < freeIfBw )
-		flag := (curAsBw < (ifec.maxIfBw >> 1 + ifec.maxIfBw >> 2)) && (curAsBw < asInfo.maxBw || curAsBw < freeIfBw)
-		if flag {
-			asInfo.addPktToAvg(length, true)
-			return true
-		}
+	// maxBw == -1 means the AS is explicitly unmetered.
+	if known && maxBw == -1 {
+		return true, nil
+	}
+	// maxBw == 0 means the AS has no reservation at all.
+	if known && maxBw == 0 {
+		metrics.PktsDropPerAs.With(asInfo.Labels).Inc()
+		return false, &Violation{IfID: ifid, IA: isdas, Limit: 0, Observed: 0}
 	}
 
-	metrics.CurBwPerAs.With(labels).Set(float64(avg))
-	metrics.PktsDropPerAs.With(labels).Inc()
-	return false
+	now := int64(monotime.Now())
+	ifec.ifBucket.refill(now)
+	asInfo.bucket.refill(now)
+	asInfo.ceil.refill(now)
+
+	n := int64(length)
+	if !asInfo.ceil.take(n) {
+		// The AS has hit its ceiling rate even counting borrowed
+		// capacity; no amount of interface headroom can help it.
+		metrics.PktsDropPerAs.With(asInfo.Labels).Inc()
+		observed := atomic.LoadInt64(&asInfo.ceil.rate)
+		return false, &Violation{IfID: ifid, IA: isdas, Limit: maxBw, Observed: observed}
+	}
+	if asInfo.bucket.take(n) {
+		ifec.observe(asInfo)
+		return true, nil
+	}
+	// The AS' own bucket can't cover the packet; try to borrow the
+	// packet's full length from the interface's spare capacity. Whatever
+	// is left in the AS' own bucket is not debited.
+	if ifec.ifBucket.take(n) {
+		ifec.observe(asInfo)
+		return true, nil
+	}
+	// No spare interface capacity is available: give back the ceiling
+	// tokens we provisionally took, since the packet won't be sent.
+	asInfo.ceil.give(n)
+	metrics.PktsDropPerAs.With(asInfo.Labels).Inc()
+	return false, &Violation{IfID: ifid, IA: isdas, Limit: maxBw, Observed: maxBw}
 }
 
-func (ifec *IFEContainer) getUsedIfBw() int64 {
-	eT := time.Since(ifec.tUsedIfBw)
+// observe exports the current fill level of the AS and interface buckets
+// so operators can see bandwidth pressure build up before packets start
+// being dropped.
+func (ifec *IFEContainer) observe(asInfo *ASEInformation) {
+	metrics.AsBucketLevel.With(asInfo.Labels).Set(float64(asInfo.bucket.level()))
+	metrics.IfBucketLevel.With(ifec.ifLabels).Set(float64(ifec.ifBucket.level()))
+}
 
-	if eT.Seconds() >= 5 {
-		usedIfBw := int64(0)
-		for _, avg := range ifec.avgs {
-			_, curBw := avg.getAvgs(false)
-			usedIfBw += curBw
-		}
-		ifec.tUsedIfBw = time.Now()
-	}
+// tokenBucket is a classical token bucket: it holds up to burst bytes of
+// credit, replenished continuously at rate bytes/s. The hot path (take)
+// is a handful of atomic operations, with no locking.
+type tokenBucket struct {
+	// rate and burst are read on every refill and written by the control
+	// API (see control.go) on a live limit update; both are accessed only
+	// via atomic.
+	rate  int64 // bytes/s
+	burst int64 // bytes
+	// tokens is the current credit, in bytes. Accessed only via atomic.
+	tokens int64
+	// lastRefill is the monotime (ns) of the last refill. Accessed only
+	// via atomic.
+	lastRefill int64
+}
 
-	return ifec.usedIfBw
+func newTokenBucket(rate, burst int64) *tokenBucket {
+	return &tokenBucket{
+		rate:       rate,
+		burst:      burst,
+		tokens:     burst,
+		lastRefill: int64(monotime.Now()),
+	}
 }
 
-// getBWInfo() checks if there is a moving average for addr and returns it. If not it
-// returns the moving average for unknown ASes.
-func (ifec *IFEContainer) getBWInfo(addr addr.ISD_AS) ASEInformation {
-	info, exists := ifec.avgs[addr.Uint32()]
-	if exists {
-		return *info, true
+// refill credits the bucket for the time elapsed since its last refill,
+// clamped to burst. Concurrent callers CAS lastRefill forward from the
+// value they observed; a caller whose CAS loses always re-reads the
+// latest lastRefill and re-checks now <= last, so an out-of-order now
+// (e.g. a stale reading racing a fresher one) never claims a stretch of
+// time another caller already accounted for, and the bucket never
+// double-credits.
+func (tb *tokenBucket) refill(now int64) {
+	for {
+		last := atomic.LoadInt64(&tb.lastRefill)
+		if now <= last {
+			return
+		}
+		rate := atomic.LoadInt64(&tb.rate)
+		add := (now - last) * rate / int64(time.Second)
+		if add <= 0 {
+			// Less than one token has accrued: leave lastRefill as it is,
+			// so this elapsed time still counts towards a later refill.
+			return
+		}
+		if !atomic.CompareAndSwapInt64(&tb.lastRefill, last, now) {
+			continue
+		}
+		burst := atomic.LoadInt64(&tb.burst)
+		for {
+			old := atomic.LoadInt64(&tb.tokens)
+			next := old + add
+			if next > burst {
+				next = burst
+			}
+			if atomic.CompareAndSwapInt64(&tb.tokens, old, next) {
+				return
+			}
+		}
 	}
-	return ifec.unknown, false
 }
 
-// getAvg() returns the current moving average in bits.
-func (info *ASEInformation) getAvg() int64 {
-	return info.movAvg.getAverage() * 8
+// setLimits updates the bucket's rate and burst in place, so a live
+// control-API update takes effect without replacing the bucket (and
+// losing its current fill level).
+func (tb *tokenBucket) setLimits(rate, burst int64) {
+	atomic.StoreInt64(&tb.rate, rate)
+	atomic.StoreInt64(&tb.burst, burst)
 }
 
-func (info *ASEInformation) getAvgs(unknown bool) (int64, int64) {
-	if !unknown && info.maxBw == 0 {
-		return 0, 0
+// take atomically debits n bytes of credit, failing without side effects
+// if the bucket doesn't hold enough.
+func (tb *tokenBucket) take(n int64) bool {
+	for {
+		old := atomic.LoadInt64(&tb.tokens)
+		if old < n {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&tb.tokens, old, old-n) {
+			return true
+		}
 	}
-	oldBw := info.curBw
-	info.curBw = info.movAvg.getAverage() * 8
-	return oldBw, info.curBw
 }
 
-// addPktToAvg() adds the packet to the moving average
-func (info *ASEInformation) addPktToAvg(length int, unknown bool) {
-	if info.maxBw != 0 || unknown {
-		info.movAvg.add(length)
+// give credits n bytes back, e.g. to undo a take() that turned out not to
+// be usable. It never pushes the bucket past burst.
+func (tb *tokenBucket) give(n int64) {
+	burst := atomic.LoadInt64(&tb.burst)
+	for {
+		old := atomic.LoadInt64(&tb.tokens)
+		next := old + n
+		if next > burst {
+			next = burst
+		}
+		if atomic.CompareAndSwapInt64(&tb.tokens, old, next) {
+			return
+		}
 	}
 }
 
-// addPktToAvg() adds the length of the packet in bytes to the moving average.
-func (info *ASEInformation) addPktToAvg2(length int) {
-	info.movAvg.add(length)
+// level returns the current fill level, for metrics.
+func (tb *tokenBucket) level() int64 {
+	return atomic.LoadInt64(&tb.tokens)
 }
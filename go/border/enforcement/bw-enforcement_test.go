@@ -0,0 +1,191 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforcement
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+func TestTokenBucketTakeGive(t *testing.T) {
+	tb := newTokenBucket(100, 10)
+	if !tb.take(10) {
+		t.Fatal("expected take(10) to succeed from a full 10-byte burst")
+	}
+	if tb.take(1) {
+		t.Fatal("expected take(1) to fail once the bucket is empty")
+	}
+	tb.give(5)
+	if tb.level() != 5 {
+		t.Fatalf("level() = %d, want 5", tb.level())
+	}
+	tb.give(100)
+	if tb.level() != 10 {
+		t.Fatalf("give() must clamp to burst: level() = %d, want 10", tb.level())
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	tb := newTokenBucket(100, 10)
+	tb.take(10)
+	tb.refill(tb.lastRefill + int64(1e9)) // +1s at 100 bytes/s
+	if tb.level() != 10 {
+		t.Fatalf("expected a 1s refill at 100B/s to fill a 10-byte burst, got %d", tb.level())
+	}
+}
+
+func TestTokenBucketRefillNoDoubleCredit(t *testing.T) {
+	tb := newTokenBucket(100, 1000)
+	tb.take(1000)
+	now := tb.lastRefill + int64(1e9)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tb.refill(now)
+		}()
+	}
+	wg.Wait()
+	if tb.level() != 100 {
+		t.Fatalf("concurrent refill() calls must credit the elapsed time exactly once: level() = %d, want 100",
+			tb.level())
+	}
+}
+
+func TestTokenBucketRefillOutOfOrderNow(t *testing.T) {
+	// Concurrent callers observe different monotime readings (e.g. one
+	// goroutine reads "now" slightly before another, then loses the race
+	// to call refill). lastRefill must end up at the largest now seen,
+	// and the credited amount must match exactly one pass over the whole
+	// elapsed interval: an unconditional atomic.Swap would let a smaller
+	// now clobber lastRefill after a larger one already advanced it,
+	// silently re-crediting the overlap.
+	tb := newTokenBucket(100, 10000)
+	tb.take(10000)
+	base := tb.lastRefill
+	nows := make([]int64, 32)
+	for i := range nows {
+		nows[i] = base + int64(i+1)*int64(1e8) // staggered by 0.1s each
+	}
+	var wg sync.WaitGroup
+	for _, n := range nows {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tb.refill(n)
+		}()
+	}
+	wg.Wait()
+
+	if tb.lastRefill != base+32*int64(1e8) {
+		t.Fatalf("lastRefill = %d, want %d (the largest now seen)", tb.lastRefill, base+32*int64(1e8))
+	}
+	want := int64(float64(32*1e8) * 100 / 1e9) // 32*0.1s at 100B/s = 320 bytes
+	if tb.level() != want {
+		t.Fatalf("level() = %d, want %d: out-of-order refill calls must credit the full "+
+			"interval exactly once", tb.level(), want)
+	}
+}
+
+func TestCanForwardBorrowFromInterface(t *testing.T) {
+	// ceilFactor 10 gives the AS a high ceiling, so once its own 10-byte
+	// burst is exhausted it can still borrow from the otherwise-idle
+	// interface bucket.
+	ifec := NewIFEContainer(1, 1000, 1000)
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	ifec.AddAS(isdas, 10, 10, 10, nil)
+
+	if ok, _ := ifec.canForward(1, isdas, 10); !ok {
+		t.Fatalf("expected the first 10-byte packet to fit the AS' own burst")
+	}
+	ok, v := ifec.canForward(1, isdas, 500)
+	if !ok || v != nil {
+		t.Fatalf("expected the AS to borrow from the idle interface once its own burst was spent")
+	}
+}
+
+func TestCanForwardUnknownASUsesSharedBucket(t *testing.T) {
+	ifec := NewIFEContainer(1, 1000, 1000)
+	// isdas was never added via AddAS, so it's served from the shared
+	// "unknown" bucket sized by defaultUnknownShare.
+	isdas := addr.ISD_AS{I: 1, A: 99}
+	if ok, v := ifec.canForward(1, isdas, 100); !ok || v != nil {
+		t.Fatalf("expected an unrecognized AS to be served from the shared unknown bucket")
+	}
+}
+
+func TestCanForwardCeilingBlocksBorrow(t *testing.T) {
+	// ceilFactor of 1.0 (the default) means the AS may never exceed its
+	// own guaranteed rate, no matter how idle the interface is.
+	ifec := NewIFEContainer(1, 1000, 1000)
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	ifec.AddAS(isdas, 100, 100, defaultCeilFactor, nil)
+
+	ok, v := ifec.canForward(1, isdas, 100)
+	if !ok {
+		t.Fatalf("expected the first 100-byte packet to fit the AS' own burst")
+	}
+	ok, v = ifec.canForward(1, isdas, 1)
+	if ok || v == nil {
+		t.Fatalf("expected the ceiling to block a packet once the AS' own burst is exhausted")
+	}
+	if v.Limit != 100 {
+		t.Fatalf("Violation.Limit = %d, want 100", v.Limit)
+	}
+}
+
+func TestCanForwardUnmetered(t *testing.T) {
+	ifec := NewIFEContainer(1, 1000, 1000)
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	ifec.AddAS(isdas, -1, 0, 0, nil)
+
+	if ok, _ := ifec.canForward(1, isdas, 1<<20); !ok {
+		t.Fatal("an AS with maxBw == -1 must never be blocked")
+	}
+}
+
+func TestCanForwardNoReservation(t *testing.T) {
+	// maxBw == 0 for an AS with an explicit (known) reservation means it
+	// has none at all, and is dropped before any bucket is even checked,
+	// unlike an unrecognized AS which shares the unknown bucket instead.
+	ifec := NewIFEContainer(1, 1000, 1000)
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	ifec.AddAS(isdas, 0, 0, defaultCeilFactor, nil)
+	ok, v := ifec.canForward(1, isdas, 1)
+	if ok || v == nil {
+		t.Fatal("expected an AS with an explicit 0-byte reservation to be dropped")
+	}
+}
+
+func TestSetUnknownShare(t *testing.T) {
+	bwe := NewBWEnforcer(true)
+	var ifid common.IFIDType = 1
+	bwe.SetInterfaceBandwidth(ifid, 1000, 1000)
+	if err := bwe.SetUnknownShare(ifid, 50, 50); err != nil {
+		t.Fatalf("SetUnknownShare failed: %v", err)
+	}
+	ifec := bwe.Interfaces()[ifid]
+	if ifec.unknown.bucket.burst != 50 {
+		t.Fatalf("unknown bucket burst = %d, want 50", ifec.unknown.bucket.burst)
+	}
+	if err := bwe.SetUnknownShare(2, 50, 50); err == nil {
+		t.Fatal("expected an error for an unknown interface")
+	}
+}
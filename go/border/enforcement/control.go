@@ -0,0 +1,204 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains the control-plane API of the enforcement package: the
+// setters, getters and drop-event feed that go/border/ctrlapi drives to let
+// operators change BW policy live, without the data plane ever blocking on
+// a lock.
+package enforcement
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+// ASLimit describes the configured reservation for a single AS, for
+// ListASLimits.
+type ASLimit struct {
+	IA    addr.ISD_AS
+	MaxBw int64
+	Burst int64
+}
+
+// ASStats describes the live bucket state for a single AS, for
+// GetEnforcementStats.
+type ASStats struct {
+	IA              addr.ISD_AS
+	MaxBw           int64
+	BucketLevel     int64
+	CeilBucketLevel int64
+}
+
+// InterfaceStats describes the live bucket state for an interface and all
+// of its known ASes.
+type InterfaceStats struct {
+	IfBucketLevel int64
+	ASes          []ASStats
+}
+
+// DropEvent is emitted whenever canForward drops a packet, for WatchDrops.
+type DropEvent struct {
+	IfID   common.IFIDType
+	IA     addr.ISD_AS
+	Length int
+	Time   time.Time
+}
+
+// SetInterfaceBandwidth installs or updates the parent bucket for ifid. If
+// the interface is new, its AS map starts out empty.
+func (bwe *BWEnforcer) SetInterfaceBandwidth(ifid common.IFIDType, maxBw, burst int64) {
+	if ifec, ex := bwe.Interfaces()[ifid]; ex {
+		ifec.ifBucket.setLimits(maxBw, burst)
+		return
+	}
+	old := bwe.Interfaces()
+	next := make(ifaceMapType, len(old)+1)
+	for k, v := range old {
+		next[k] = v
+	}
+	next[ifid] = NewIFEContainer(ifid, maxBw, burst)
+	bwe.ifaces.Store(next)
+}
+
+// SetASBandwidth installs or updates the reservation for isdas on ifid. A
+// ceilFactor of 0 means "use the default" (see defaultCeilFactor).
+func (bwe *BWEnforcer) SetASBandwidth(ifid common.IFIDType, isdas addr.ISD_AS, maxBw, burst int64,
+	ceilFactor float64) *common.Error {
+
+	ifec, ex := bwe.Interfaces()[ifid]
+	if !ex {
+		return common.NewError("Unknown interface", "ifid", ifid)
+	}
+	if ceilFactor == 0 {
+		ceilFactor = defaultCeilFactor
+	}
+	if asInfo, ex := ifec.asMap()[isdas.Uint32()]; ex {
+		asInfo.setLimits(maxBw, burst, ceilFactor)
+		return nil
+	}
+	ifec.AddAS(isdas, maxBw, burst, ceilFactor, asLabels(ifid, isdas))
+	return nil
+}
+
+// setLimits updates an existing AS bucket's limits in place, so a live
+// update doesn't reset the bucket's current fill level.
+func (info *ASEInformation) setLimits(maxBw, burst int64, ceilFactor float64) {
+	atomic.StoreInt64(&info.maxBw, maxBw)
+	info.bucket.setLimits(maxBw, burst)
+	info.ceil.setLimits(int64(float64(maxBw)*ceilFactor), burst)
+}
+
+// SetUnknownShare updates the guaranteed rate and burst given to the
+// shared bucket used by ASes with no explicit reservation on ifid. It
+// makes the "unknown ASes share a fair rate" policy (see
+// defaultUnknownShare) a live, per-interface knob instead of a constant.
+func (bwe *BWEnforcer) SetUnknownShare(ifid common.IFIDType, maxBw, burst int64) *common.Error {
+	ifec, ex := bwe.Interfaces()[ifid]
+	if !ex {
+		return common.NewError("Unknown interface", "ifid", ifid)
+	}
+	ifec.unknown.setLimits(maxBw, burst, defaultCeilFactor)
+	return nil
+}
+
+// ListASLimits returns the configured (not live) limits for every AS with
+// an explicit reservation on ifid.
+func (bwe *BWEnforcer) ListASLimits(ifid common.IFIDType) ([]ASLimit, *common.Error) {
+	ifec, ex := bwe.Interfaces()[ifid]
+	if !ex {
+		return nil, common.NewError("Unknown interface", "ifid", ifid)
+	}
+	var limits []ASLimit
+	for _, info := range ifec.asMap() {
+		limits = append(limits, ASLimit{
+			IA:    info.IA,
+			MaxBw: atomic.LoadInt64(&info.maxBw),
+			Burst: atomic.LoadInt64(&info.bucket.burst),
+		})
+	}
+	return limits, nil
+}
+
+// GetEnforcementStats returns the current bucket fill levels for ifid,
+// letting an operator see pressure build up before drops start.
+func (bwe *BWEnforcer) GetEnforcementStats(ifid common.IFIDType) (*InterfaceStats, *common.Error) {
+	ifec, ex := bwe.Interfaces()[ifid]
+	if !ex {
+		return nil, common.NewError("Unknown interface", "ifid", ifid)
+	}
+	stats := &InterfaceStats{IfBucketLevel: ifec.ifBucket.level()}
+	for _, info := range ifec.asMap() {
+		stats.ASes = append(stats.ASes, ASStats{
+			IA:              info.IA,
+			MaxBw:           atomic.LoadInt64(&info.maxBw),
+			BucketLevel:     info.bucket.level(),
+			CeilBucketLevel: info.ceil.level(),
+		})
+	}
+	return stats, nil
+}
+
+// dropWatchers holds the subscribers registered via Subscribe.
+var dropWatchersMu sync.Mutex
+var dropWatchers = map[*BWEnforcer][]chan DropEvent{}
+
+// Subscribe registers a channel that receives a DropEvent for every packet
+// this enforcer drops from now on. The returned func unsubscribes. The
+// channel is buffered and drops events rather than block the data plane
+// if the subscriber falls behind.
+func (bwe *BWEnforcer) Subscribe() (<-chan DropEvent, func()) {
+	ch := make(chan DropEvent, 64)
+	dropWatchersMu.Lock()
+	dropWatchers[bwe] = append(dropWatchers[bwe], ch)
+	dropWatchersMu.Unlock()
+	unsub := func() {
+		dropWatchersMu.Lock()
+		defer dropWatchersMu.Unlock()
+		subs := dropWatchers[bwe]
+		for i, c := range subs {
+			if c == ch {
+				dropWatchers[bwe] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+	return ch, unsub
+}
+
+// reportDrop fans a DropEvent out to every current subscriber, without
+// blocking if a subscriber's channel is full.
+func (bwe *BWEnforcer) reportDrop(ifid common.IFIDType, isdas addr.ISD_AS, length int) {
+	dropWatchersMu.Lock()
+	subs := dropWatchers[bwe]
+	dropWatchersMu.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+	ev := DropEvent{IfID: ifid, IA: isdas, Length: length, Time: time.Now()}
+	for _, ch := range subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// asLabels builds the prometheus labels for an AS on a given interface.
+func asLabels(ifid common.IFIDType, isdas addr.ISD_AS) map[string]string {
+	return map[string]string{"ifid": ifid.String(), "isd_as": isdas.String()}
+}
@@ -0,0 +1,37 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforcement
+
+import "github.com/gavv/monotime"
+
+// RateLimiter is a standalone token bucket, exported for callers outside
+// this package (e.g. go/border's SCMP notification path) that need basic
+// rate limiting without duplicating the bucket math in bw-enforcement.go.
+type RateLimiter struct {
+	tb *tokenBucket
+}
+
+// NewRateLimiter creates a RateLimiter with the given rate (units/s) and
+// burst (units). Units are caller-defined, e.g. bytes or events.
+func NewRateLimiter(rate, burst int64) *RateLimiter {
+	return &RateLimiter{tb: newTokenBucket(rate, burst)}
+}
+
+// Allow reports whether n units are within the current rate limit,
+// debiting them if so.
+func (rl *RateLimiter) Allow(n int64) bool {
+	rl.tb.refill(int64(monotime.Now()))
+	return rl.tb.take(n)
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file adds the bucket-fill gauges the HTB-based BW enforcer (see
+// go/border/enforcement) exports, alongside the existing CurBwPerAs/
+// PktsDropPerAs counters.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// AsBucketLevel is the current fill level, in bytes, of an AS' own
+	// token bucket.
+	AsBucketLevel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "border",
+		Subsystem: "bw_enforcement",
+		Name:      "as_bucket_level_bytes",
+		Help:      "Current token bucket fill level for a single AS' bandwidth reservation.",
+	}, []string{"isd_as", "ifid"})
+
+	// IfBucketLevel is the current fill level, in bytes, of the spare
+	// capacity an interface has available for ASes to borrow. It's keyed
+	// by ifid only: the borrowable pool belongs to the interface, not any
+	// one AS, so labeling it by isd_as as well would just multiply one
+	// series into one-per-AS-per-interface for no reason.
+	IfBucketLevel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "border",
+		Subsystem: "bw_enforcement",
+		Name:      "if_bucket_level_bytes",
+		Help:      "Current token bucket fill level for an interface's borrowable bandwidth.",
+	}, []string{"ifid"})
+)
+
+func init() {
+	prometheus.MustRegister(AsBucketLevel, IfBucketLevel)
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file adds the metrics for go/border/sched's per-class DRR
+// scheduling between handleSock and packet processing.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// PktsSchedDropped counts packets dropped because their traffic
+	// class' ring was full.
+	PktsSchedDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "border",
+		Subsystem: "sched",
+		Name:      "pkts_dropped_total",
+		Help:      "Packets dropped because their traffic class' ring was full.",
+	})
+
+	// SchedQueueDelay is the time a packet spent queued in its traffic
+	// class' ring before being scheduled, per class.
+	SchedQueueDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "border",
+		Subsystem: "sched",
+		Name:      "queue_delay_seconds",
+		Help:      "Time a packet spent queued in its traffic class' ring before being scheduled.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"class"})
+)
+
+func init() {
+	prometheus.MustRegister(PktsSchedDropped, SchedQueueDelay)
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file adds the counter for SCMP_T_POLICY_BW_EXCEEDED notifications
+// (see go/border/bwscmp.go).
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PktsScmpBwExceeded counts SCMP_T_POLICY_BW_EXCEEDED notifications sent,
+// per source AS and ingress interface.
+var PktsScmpBwExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "border",
+	Subsystem: "bw_enforcement",
+	Name:      "pkts_scmp_bw_exceeded_total",
+	Help:      "SCMP_T_POLICY_BW_EXCEEDED notifications sent, per source AS and ingress interface.",
+}, []string{"isd_as", "ifid"})
+
+func init() {
+	prometheus.MustRegister(PktsScmpBwExceeded)
+}
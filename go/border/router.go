@@ -17,8 +17,12 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/gavv/monotime"
@@ -26,16 +30,16 @@ import (
 	logext "github.com/inconshreveable/log15/ext"
 
 	"github.com/netsec-ethz/scion/go/border/conf"
+	"github.com/netsec-ethz/scion/go/border/ctrlapi"
 	"github.com/netsec-ethz/scion/go/border/enforcement"
 	"github.com/netsec-ethz/scion/go/border/metrics"
 	"github.com/netsec-ethz/scion/go/border/rcmn"
 	"github.com/netsec-ethz/scion/go/border/rctx"
 	"github.com/netsec-ethz/scion/go/border/rpkt"
+	"github.com/netsec-ethz/scion/go/border/sched"
 	"github.com/netsec-ethz/scion/go/lib/assert"
 	"github.com/netsec-ethz/scion/go/lib/common"
 	"github.com/netsec-ethz/scion/go/lib/log"
-	"time"
-	"fmt"
 	"github.com/netsec-ethz/scion/go/lib/ringbuf"
 )
 
@@ -61,14 +65,32 @@ type Router struct {
 	// fBwEnf is a flag to indicate whether to do BW enforcement or not.
 	fBwEnf bool
 	//ingressBWE holds all information to do BW enforcement on ingress pkts.
-	ingressBWE enforcement.BWEnforcer
+	ingressBWE *enforcement.BWEnforcer
 	//engressBWE holds all information to do BW enforcement on engress pkts.
-	egresseBWE enforcement.BWEnforcer
+	egresseBWE *enforcement.BWEnforcer
+	//ctrlAPI serves live control-plane updates to ingressBWE/egresseBWE.
+	ctrlAPI *ctrlapi.Server
+	//bwScmpLimiter rate-limits SCMP_T_POLICY_BW_EXCEEDED notifications.
+	bwScmpLimiter *bwScmpLimiter
+	//classifier assigns packets to a sched.Class; it's shared by every
+	//handleSock goroutine so a reload of AS classes applies router-wide.
+	classifier *sched.DefaultClassifier
+	//schedWeights holds the current [4]int64 of per-class DRR weights, as
+	//last loaded from config; handleSock reads it when creating a
+	//socket's ClassifiedRing, so a reload's weights take effect the next
+	//time a socket is (re)established.
+	schedWeights atomic.Value
 }
 
 func NewRouter(id, confDir string) (*Router, *common.Error) {
 	metrics.Init(id)
-	r := &Router{Id: id, confDir: confDir}
+	r := &Router{
+		Id:            id,
+		confDir:       confDir,
+		bwScmpLimiter: newBwScmpLimiter(),
+		classifier:    sched.NewDefaultClassifier(),
+	}
+	r.schedWeights.Store(sched.DefaultWeights)
 	if err := r.setup(); err != nil {
 		return nil, err
 	}
@@ -76,9 +98,35 @@ func NewRouter(id, confDir string) (*Router, *common.Error) {
 	//set-up bandwidth enforcement.
 	r.setupBwEnforcement(confDir)
 
+	//set-up the control API, so operators can push BW policy updates
+	//without a SIGHUP.
+	r.ctrlAPI = ctrlapi.NewServer(ctrlapi.Enforcers{
+		Ingress: r.ingressBWE,
+		Egress:  r.egresseBWE,
+	}, r.reloadConfig)
+	r.ctrlAPI.AuthToken = r.loadCtrlAPIToken()
+
 	return r, nil
 }
 
+// ctrlAPITokenFile is the name, relative to confDir, of the file holding
+// the bearer token ctrlapi callers must present. Its absence leaves the
+// control API unauthenticated, which is only safe for a loopback-only
+// bind.
+const ctrlAPITokenFile = "ctrlapi.token"
+
+// loadCtrlAPIToken reads the ctrlapi bearer token from confDir, returning
+// "" (no authentication) if it isn't configured.
+func (r *Router) loadCtrlAPIToken() string {
+	path := filepath.Join(r.confDir, ctrlAPITokenFile)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Warn("No ctrlapi auth token configured; control API is unauthenticated", "path", path)
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
 // Run sets up networking, and starts go routines for handling the main packet
 // processing as well as various other router functions.
 func (r *Router) Run() *common.Error {
@@ -86,6 +134,7 @@ func (r *Router) Run() *common.Error {
 	go r.IFStateUpdate()
 	go r.RevInfoFwd()
 	go r.confSig()
+	go r.runCtrlAPI()
 	// TODO(shitz): Here should be some code to periodically check the discovery
 	// service for updated info.
 	var wait chan struct{}
@@ -93,27 +142,54 @@ func (r *Router) Run() *common.Error {
 	return nil
 }
 
+// runCtrlAPI starts the control-API subsystem and logs (rather than fails)
+// if it can't bind, since the data plane must keep running regardless.
+func (r *Router) runCtrlAPI() {
+	if err := r.ctrlAPI.ListenAndServe(); err != nil {
+		log.Error("ctrlapi server stopped", err.Ctx...)
+	}
+}
+
 // confSig handles reloading the configuration when SIGHUP is received.
 func (r *Router) confSig() {
 	for range sighup {
-		var err *common.Error
-		var config *conf.Conf
-		if config, err = r.loadNewConfig(); err != nil {
+		if err := r.reloadConfig(); err != nil {
 			log.Error("Error reloading config", err.Ctx...)
 			continue
 		}
-		if err = r.setupNewContext(config); err != nil {
-			log.Error("Error setting up new context", err.Ctx...)
-			continue
-		}
 		log.Info("Config reloaded")
 	}
 }
 
+// reloadConfig reloads the on-disk configuration and applies it to a new
+// router context. It's shared by the SIGHUP handler above and by the
+// ctrlapi ReloadConfig RPC, so both paths behave identically.
+func (r *Router) reloadConfig() *common.Error {
+	var err *common.Error
+	var config *conf.Conf
+	if config, err = r.loadNewConfig(); err != nil {
+		return err
+	}
+	if err = r.setupNewContext(config); err != nil {
+		return err
+	}
+	// Reload BW policy (enforcement limits, scheduler weights, and AS
+	// traffic classes) from the same confDir, so this path - shared by
+	// the SIGHUP handler and the ctrlapi ReloadConfig RPC - actually
+	// reloads it, instead of only the unrelated router context above.
+	r.setupBwEnforcement(r.confDir)
+	return nil
+}
+
 func (r *Router) handleSock(s *rctx.Sock, stop, stopped chan struct{}) {
 	defer liblog.PanicLog()
 	defer close(stopped)
 	pkts := make(ringbuf.EntryList, 256)
+	// cr buffers this socket's packets per traffic class, so a flood of
+	// best-effort/scavenger traffic can't delay control-plane packets
+	// that arrived in the same read batch.
+	cr := sched.NewClassifiedRing(r.classifier, cap(pkts))
+	cr.SetWeights(r.schedWeights.Load().([4]int64))
 	log.Debug("handleSock starting", "sock", *s)
 	for {
 		n := s.Ring.Read(pkts, true)
@@ -123,10 +199,20 @@ func (r *Router) handleSock(s *rctx.Sock, stop, stopped chan struct{}) {
 		}
 		for i := 0; i < n; i++ {
 			rp := pkts[i].(*rpkt.RtrPkt)
+			if !cr.Enqueue(rp) {
+				metrics.PktsSchedDropped.Inc()
+				rp.Release()
+			}
+			pkts[i] = nil
+		}
+		for {
+			rp, _, ok := cr.Pick(false)
+			if !ok {
+				break
+			}
 			r.processPacket(rp)
 			metrics.PktProcessTime.Add(monotime.Since(rp.TimeIn).Seconds())
 			rp.Release()
-			pkts[i] = nil
 		}
 	}
 }
@@ -153,31 +239,23 @@ func (r *Router) processPacket(rp *rpkt.RtrPkt) {
 		r.handlePktError(rp, err, "Error parsing packet")
 		return
 	}
-	//Check that the packet does not belong to an AS that is exceeding its
+	// Check that the packet does not belong to an AS that is exceeding its
 	// BW limitations.
-	strng := "unknown"
-	start := time.Now()
 	if r.fBwEnf {
 		if r.ingressBWE.DoEnforcement &&
 			(rp.DirFrom == rcmn.DirExternal && (rp.DirTo == rcmn.DirLocal || rp.DirTo == rcmn.DirSelf ||
 				rp.DirTo == rcmn.DirExternal)) {
-			strng = "ingress"
-			if !r.ingressBWE.Check(rp) {
-				strng = "dropped"
+			if ok, v := r.ingressBWE.Check(rp); !ok {
+				r.notifyBwExceeded(rp, v)
+				return
 			}
 		} else if r.egresseBWE.DoEnforcement && (rp.DirFrom == rcmn.DirLocal && rp.DirTo == rcmn.DirExternal) {
-			strng = "egress"
-			if !r.egresseBWE.Check(rp) {
-				strng = "dropped"
+			if ok, v := r.egresseBWE.Check(rp); !ok {
+				r.notifyBwExceeded(rp, v)
+				return
 			}
 		}
 	}
-	el := time.Since(start)
-	fmt.Printf("%s %d\n", strng, el.Nanoseconds())
-
-	if strng == "dropped" {
-		return
-	}
 	// Validation looks for errors in the packet that didn't break basic
 	// parsing.
 	if err := rp.Validate(); err != nil {
@@ -0,0 +1,125 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file defines the traffic classes a border router schedules
+// between, and the default classifier that assigns a packet to one.
+package sched
+
+import (
+	"sync/atomic"
+
+	"github.com/netsec-ethz/scion/go/border/rpkt"
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+// Class is one of a fixed set of traffic classes a ClassifiedRing
+// schedules between. The zero value, ClassControl, is the
+// highest-priority class.
+type Class int
+
+const (
+	// ClassControl is beacons, PCBs, revocations and other SCMP/control
+	// traffic: it must get through even when the router is saturated.
+	ClassControl Class = iota
+	// ClassLowLatency is traffic from ASes configured as
+	// latency-sensitive.
+	ClassLowLatency
+	// ClassBestEffort is ordinary, unclassified traffic.
+	ClassBestEffort
+	// ClassScavenger gets the smallest DRR quantum (see DefaultWeights);
+	// it's for traffic explicitly marked as background/bulk.
+	ClassScavenger
+	// numClasses is the number of Class values above; it's the size of
+	// every per-class array in this package.
+	numClasses
+)
+
+var classNames = [numClasses]string{"control", "low-latency", "best-effort", "scavenger"}
+
+func (c Class) String() string {
+	if int(c) < 0 || int(c) >= len(classNames) {
+		return "unknown"
+	}
+	return classNames[c]
+}
+
+// Classifier assigns a Class to a packet. It's called once per packet on
+// the hot path, so implementations should avoid allocating or blocking.
+type Classifier interface {
+	Classify(rp *rpkt.RtrPkt) Class
+}
+
+// DefaultClassifier puts SCMP packets in ClassControl, and otherwise uses
+// the per-AS override that operators configure alongside BW limits (see
+// go/border/bw_setup.go), falling back to ClassBestEffort. It does not
+// inspect SCION extensions or the path type. Operators needing more than that can supply
+// their own Classifier to NewClassifiedRing instead (e.g. a Lua or
+// Go-plugin hook).
+type DefaultClassifier struct {
+	// asClasses holds the current map[uint32]Class, swapped atomically
+	// so a reload never blocks Classify.
+	asClasses atomic.Value
+}
+
+// NewDefaultClassifier creates a DefaultClassifier with no AS overrides.
+func NewDefaultClassifier() *DefaultClassifier {
+	dc := &DefaultClassifier{}
+	dc.asClasses.Store(make(map[uint32]Class))
+	return dc
+}
+
+// SetASClass overrides the class used for traffic from isdas, via
+// copy-on-write so Classify never observes a partially updated map.
+func (dc *DefaultClassifier) SetASClass(isdas addr.ISD_AS, class Class) {
+	old := dc.asClasses.Load().(map[uint32]Class)
+	next := make(map[uint32]Class, len(old)+1)
+	for k, v := range old {
+		next[k] = v
+	}
+	next[isdas.Uint32()] = class
+	dc.asClasses.Store(next)
+}
+
+// Classify implements Classifier.
+func (dc *DefaultClassifier) Classify(rp *rpkt.RtrPkt) Class {
+	if isControlPkt(rp) {
+		return ClassControl
+	}
+	if srcIA, err := rp.SrcIA(); err == nil {
+		if class, ok := dc.asClasses.Load().(map[uint32]Class)[srcIA.Uint32()]; ok {
+			return class
+		}
+	}
+	return ClassBestEffort
+}
+
+// ParseClass parses a class name as produced by Class.String (e.g.
+// "scavenger"), for loading AS class overrides from configuration. It
+// reports false for anything else, including "unknown".
+func ParseClass(s string) (Class, bool) {
+	for i, name := range classNames {
+		if name == s {
+			return Class(i), true
+		}
+	}
+	return 0, false
+}
+
+// isControlPkt reports whether rp is an SCMP packet, which must not be
+// starved by bulk traffic. Other control-plane payloads (beacons, PCBs,
+// revocations) are not detected here.
+func isControlPkt(rp *rpkt.RtrPkt) bool {
+	return rp.CmnHdr.NextHdr == common.L4SCMP
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sched
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/scion/go/border/rpkt"
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+func TestClassString(t *testing.T) {
+	cases := map[Class]string{
+		ClassControl:      "control",
+		ClassLowLatency:   "low-latency",
+		ClassBestEffort:   "best-effort",
+		ClassScavenger:    "scavenger",
+		Class(numClasses): "unknown",
+	}
+	for class, want := range cases {
+		if got := class.String(); got != want {
+			t.Errorf("Class(%d).String() = %q, want %q", class, got, want)
+		}
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	cases := map[string]Class{
+		"control":     ClassControl,
+		"low-latency": ClassLowLatency,
+		"best-effort": ClassBestEffort,
+		"scavenger":   ClassScavenger,
+	}
+	for name, want := range cases {
+		got, ok := ParseClass(name)
+		if !ok || got != want {
+			t.Errorf("ParseClass(%q) = (%v, %v), want (%v, true)", name, got, ok, want)
+		}
+	}
+	if _, ok := ParseClass("unknown"); ok {
+		t.Error(`ParseClass("unknown") should not parse to a valid Class`)
+	}
+	if _, ok := ParseClass("bogus"); ok {
+		t.Error(`ParseClass("bogus") should not parse`)
+	}
+}
+
+func TestIsControlPkt(t *testing.T) {
+	scmp := &rpkt.RtrPkt{}
+	scmp.CmnHdr.NextHdr = common.L4SCMP
+	if !isControlPkt(scmp) {
+		t.Error("expected an SCMP packet to be classified as control")
+	}
+	udp := &rpkt.RtrPkt{}
+	udp.CmnHdr.NextHdr = common.L4UDP
+	if isControlPkt(udp) {
+		t.Error("expected a non-SCMP packet to not be classified as control")
+	}
+}
+
+func TestDefaultClassifierASOverride(t *testing.T) {
+	dc := NewDefaultClassifier()
+	isdas := addr.ISD_AS{I: 1, A: 10}
+	dc.SetASClass(isdas, ClassScavenger)
+
+	old := dc.asClasses.Load().(map[uint32]Class)
+	dc.SetASClass(addr.ISD_AS{I: 1, A: 20}, ClassLowLatency)
+	if len(old) != 1 {
+		t.Error("SetASClass must copy-on-write, not mutate the previously loaded map")
+	}
+
+	classes := dc.asClasses.Load().(map[uint32]Class)
+	if classes[isdas.Uint32()] != ClassScavenger {
+		t.Errorf("asClasses[isdas] = %v, want ClassScavenger", classes[isdas.Uint32()])
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements ClassifiedRing, a small deficit round-robin (DRR)
+// scheduler over one ringbuf.Ring per traffic class. handleSock enqueues
+// every packet it reads off the socket into a ClassifiedRing, then drains
+// it via Pick instead of processing packets in raw FIFO order, so control
+// traffic can't be starved by a best-effort or scavenger-class flood.
+package sched
+
+import (
+	"sync/atomic"
+
+	"github.com/gavv/monotime"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/netsec-ethz/scion/go/border/metrics"
+	"github.com/netsec-ethz/scion/go/border/rpkt"
+	"github.com/netsec-ethz/scion/go/lib/ringbuf"
+)
+
+// DefaultWeights gives control traffic the largest DRR quantum, and
+// scavenger traffic the smallest, without starving any class outright.
+// It's exported so callers configuring per-class weights (see
+// go/border/bw_setup.go) have something to fall back to when no
+// override is configured.
+var DefaultWeights = [numClasses]int64{4, 3, 2, 1}
+
+// ClassifiedRing fans packets out into one ringbuf.Ring per Class on
+// enqueue, and schedules between them on dequeue using DRR.
+type ClassifiedRing struct {
+	classifier Classifier
+	rings      [numClasses]*ringbuf.Ring
+	// weights holds the current [numClasses]int64, swapped atomically so
+	// a reload never blocks Pick.
+	weights atomic.Value
+	// deficit is only touched by the single goroutine calling Pick, so it
+	// needs no synchronization.
+	deficit [numClasses]int64
+	pos     int
+}
+
+// NewClassifiedRing creates a ClassifiedRing with capacity entries of
+// buffering per class.
+func NewClassifiedRing(classifier Classifier, capacity int) *ClassifiedRing {
+	cr := &ClassifiedRing{classifier: classifier}
+	for i := 0; i < numClasses; i++ {
+		cr.rings[i] = ringbuf.New(capacity, func() interface{} { return nil }, "sched_"+Class(i).String())
+	}
+	cr.weights.Store(DefaultWeights)
+	return cr
+}
+
+// SetWeights updates the DRR quantum per class; it takes effect on the
+// next scheduling sweep.
+func (cr *ClassifiedRing) SetWeights(weights [numClasses]int64) {
+	cr.weights.Store(weights)
+}
+
+// Enqueue classifies rp and pushes it onto the corresponding class' ring.
+// It reports false (dropping the packet) if that ring is full, which is
+// the scheduler's backpressure point: a flood of one class fills only
+// its own ring, leaving the others untouched.
+func (cr *ClassifiedRing) Enqueue(rp *rpkt.RtrPkt) bool {
+	class := cr.classifier.Classify(rp)
+	n := cr.rings[class].Write(ringbuf.EntryList{rp}, false)
+	return n == 1
+}
+
+// Pick returns the next packet to process, and the class it came from,
+// in DRR order. If block is true and every ring is currently empty, it
+// blocks on the control-class ring, so a newly-arriving control packet
+// is never delayed behind an idle scheduling sweep.
+func (cr *ClassifiedRing) Pick(block bool) (*rpkt.RtrPkt, Class, bool) {
+	weights := cr.weights.Load().([numClasses]int64)
+	pkts := make(ringbuf.EntryList, 1)
+	for i := 0; i < numClasses; i++ {
+		idx := Class((cr.pos + i) % numClasses)
+		if cr.deficit[idx] <= 0 {
+			cr.deficit[idx] += weights[idx]
+		}
+		if cr.deficit[idx] <= 0 {
+			continue
+		}
+		if n := cr.rings[idx].Read(pkts, false); n == 1 {
+			cr.deficit[idx]--
+			// Stay on this class until its quantum is spent, so each
+			// class gets up to weights[idx] packets per sweep.
+			cr.pos = int(idx)
+			if cr.deficit[idx] <= 0 {
+				cr.pos = (int(idx) + 1) % numClasses
+			}
+			rp := pkts[0].(*rpkt.RtrPkt)
+			cr.observe(idx, rp)
+			return rp, idx, true
+		}
+		// Ring idx is empty: don't let its deficit accumulate while
+		// it's not competing for bandwidth.
+		cr.deficit[idx] = 0
+	}
+	if !block {
+		return nil, 0, false
+	}
+	if n := cr.rings[ClassControl].Read(pkts, true); n == 1 {
+		rp := pkts[0].(*rpkt.RtrPkt)
+		cr.observe(ClassControl, rp)
+		return rp, ClassControl, true
+	}
+	return nil, 0, false
+}
+
+// observe records how long rp spent queued (since it was received) in
+// the per-class delay histogram, so operators can see whether a class is
+// starting to back up before it starts dropping.
+func (cr *ClassifiedRing) observe(class Class, rp *rpkt.RtrPkt) {
+	metrics.SchedQueueDelay.With(prometheus.Labels{"class": class.String()}).
+		Observe(monotime.Since(rp.TimeIn).Seconds())
+}
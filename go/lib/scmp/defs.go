@@ -0,0 +1,40 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file defines the SCMP message classes shared by every payload type
+// in this package; individual payloads (e.g. policy.go's
+// BWExceededInfo) each live in their own file and add a Type within one
+// of these classes.
+package scmp
+
+// Class identifies the general category of an SCMP message.
+type Class uint16
+
+// Type identifies the specific condition within a Class.
+type Type uint16
+
+const (
+	// SCMP_CLASS_GENERAL covers path-independent errors, e.g. malformed
+	// common headers.
+	SCMP_CLASS_GENERAL Class = iota
+	// SCMP_CLASS_ROUTING covers routing errors, e.g. unreachable
+	// destinations or revoked interfaces.
+	SCMP_CLASS_ROUTING
+	// SCMP_CLASS_EXT covers hop-by-hop and end-to-end extension errors.
+	SCMP_CLASS_EXT
+	// SCMP_CLASS_POLICY covers notifications about router-enforced
+	// policy, e.g. bandwidth enforcement, rather than a protocol error
+	// (see policy.go).
+	SCMP_CLASS_POLICY
+)
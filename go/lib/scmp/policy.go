@@ -0,0 +1,49 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file adds the SCMP_T_POLICY_BW_EXCEEDED payload within the
+// SCMP_CLASS_POLICY class (see defs.go).
+package scmp
+
+import (
+	"github.com/netsec-ethz/scion/go/lib/addr"
+	"github.com/netsec-ethz/scion/go/lib/common"
+)
+
+const (
+	// SCMP_T_POLICY_BW_EXCEEDED is sent back to a packet's source AS
+	// when the packet was dropped for exceeding its configured
+	// bandwidth reservation, so the sender can back off instead of
+	// retransmitting into an overload (see go/border/enforcement).
+	SCMP_T_POLICY_BW_EXCEEDED Type = 0
+)
+
+// BWExceededInfo is the SCMP_T_POLICY_BW_EXCEEDED payload: enough context
+// for the source AS' operator to tell which reservation it hit.
+type BWExceededInfo struct {
+	// IA is the offending AS.
+	IA addr.ISD_AS
+	// IfID is the router interface the packet was enforced on.
+	IfID common.IFIDType
+	// ObservedRate and Limit are both in bytes/s.
+	ObservedRate int64
+	Limit        int64
+}
+
+// Class returns SCMP_CLASS_POLICY, so BWExceededInfo satisfies whatever
+// Info interface the general SCMP payload machinery expects.
+func (info *BWExceededInfo) Class() Class { return SCMP_CLASS_POLICY }
+
+// Type returns SCMP_T_POLICY_BW_EXCEEDED.
+func (info *BWExceededInfo) Type() Type { return SCMP_T_POLICY_BW_EXCEEDED }
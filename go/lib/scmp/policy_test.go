@@ -0,0 +1,37 @@
+// Copyright 2016 ETH Zurich
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp
+
+import "testing"
+
+func TestBWExceededInfoClassAndType(t *testing.T) {
+	info := &BWExceededInfo{}
+	if info.Class() != SCMP_CLASS_POLICY {
+		t.Fatalf("Class() = %v, want SCMP_CLASS_POLICY", info.Class())
+	}
+	if info.Type() != SCMP_T_POLICY_BW_EXCEEDED {
+		t.Fatalf("Type() = %v, want SCMP_T_POLICY_BW_EXCEEDED", info.Type())
+	}
+}
+
+func TestClassValuesAreDistinct(t *testing.T) {
+	seen := map[Class]bool{}
+	for _, c := range []Class{SCMP_CLASS_GENERAL, SCMP_CLASS_ROUTING, SCMP_CLASS_EXT, SCMP_CLASS_POLICY} {
+		if seen[c] {
+			t.Fatalf("class %v assigned to more than one SCMP_CLASS_* constant", c)
+		}
+		seen[c] = true
+	}
+}